@@ -1,30 +1,155 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 
 	p "github.com/hookenz/goose/pkg"
 )
 
+const usage = `Usage:
+  goose install [--frozen] [--ignore-scripts] <package[@version]> [...]
+  goose update <package>
+  goose store prune
+  goose <package[@version]> [...]   (shorthand for "goose install")`
+
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: goose <package[@version]> [...]")
+		fmt.Println(usage)
+		return
+	}
+
+	args := os.Args[1:]
+	switch args[0] {
+	case "install":
+		runInstall(args[1:])
+	case "update":
+		runUpdate(args[1:])
+	case "store":
+		runStore(args[1:])
+	default:
+		runInstall(args)
+	}
+}
+
+func runStore(args []string) {
+	if len(args) != 1 || args[0] != "prune" {
+		fmt.Println("Usage: goose store prune")
+		return
+	}
+
+	blobs, bytes, err := p.PruneStore()
+	if err != nil {
+		fmt.Printf("Error pruning store: %v\n", err)
+		return
+	}
+	fmt.Printf("Removed %d unreferenced blob(s), freed %d bytes.\n", blobs, bytes)
+}
+
+func runInstall(args []string) {
+	frozen := false
+	ignoreScripts := false
+	var specs []string
+	for _, arg := range args {
+		switch arg {
+		case "--frozen":
+			frozen = true
+		case "--ignore-scripts":
+			ignoreScripts = true
+		default:
+			specs = append(specs, arg)
+		}
+	}
+
+	if len(specs) == 0 {
+		fmt.Println(usage)
 		return
 	}
 
-	for _, arg := range os.Args[1:] {
+	if err := p.LoadConfig(); err != nil {
+		fmt.Printf("Error loading %s: %v\n", p.ConfigName, err)
+		os.Exit(1)
+	}
+	p.SetFrozen(frozen)
+	p.SetIgnoreScripts(ignoreScripts)
+	if err := p.LoadLock(); err != nil {
+		fmt.Printf("Error loading %s: %v\n", p.LockfileName, err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	failed := false
+	for _, arg := range specs {
 		pkg, err := p.Parse(arg)
 		if err != nil {
 			fmt.Printf("Error parsing package '%s': %v\n", arg, err)
+			failed = true
 			continue
 		}
 
-		err = p.Install(pkg)
-		if err != nil {
+		if err := p.InstallContext(ctx, pkg); err != nil {
 			fmt.Printf("Error installing package '%s': %v\n", pkg.Name, err)
+			failed = true
+		}
+
+		if ctx.Err() != nil {
+			failed = true
+			break
+		}
+	}
+
+	if !frozen {
+		if err := p.SaveLock(); err != nil {
+			fmt.Printf("Error saving %s: %v\n", p.LockfileName, err)
+			failed = true
 		}
 	}
 
+	if failed {
+		os.Exit(1)
+	}
+
 	fmt.Println("All packages installed.")
 }
+
+func runUpdate(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: goose update <package>")
+		return
+	}
+
+	pkg, err := p.Parse(args[0])
+	if err != nil {
+		fmt.Printf("Error parsing package '%s': %v\n", args[0], err)
+		os.Exit(1)
+	}
+
+	if err := p.LoadConfig(); err != nil {
+		fmt.Printf("Error loading %s: %v\n", p.ConfigName, err)
+		os.Exit(1)
+	}
+	if err := p.LoadLock(); err != nil {
+		fmt.Printf("Error loading %s: %v\n", p.LockfileName, err)
+		os.Exit(1)
+	}
+	p.SetUpdateTarget(pkg.Name)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := p.InstallContext(ctx, pkg); err != nil {
+		fmt.Printf("Error updating package '%s': %v\n", pkg.Name, err)
+		os.Exit(1)
+	}
+
+	if err := p.SaveLock(); err != nil {
+		fmt.Printf("Error saving %s: %v\n", p.LockfileName, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Updated %s.\n", pkg.Name)
+}
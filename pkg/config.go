@@ -0,0 +1,126 @@
+package pkg
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ConfigName is the per-project config file, read for the --ignore-scripts
+// default and the scripts allow/deny lists. Only the handful of keys
+// goose itself understands are parsed; this is intentionally not a
+// general-purpose TOML implementation.
+const ConfigName = "goose.toml"
+
+// Config holds the settings goose reads from goose.toml, seeded with
+// whatever .npmrc already configured so either file can supply registry
+// settings and goose.toml always has the final say.
+type Config struct {
+	IgnoreScripts bool
+	ScriptsAllow  map[string]bool
+	ScriptsDeny   map[string]bool
+
+	DefaultRegistry string
+	ScopeRegistries map[string]string // "@scope" -> base registry URL
+	RegistryTokens  map[string]string // host -> bearer token
+}
+
+var config = &Config{ScriptsAllow: map[string]bool{}, ScriptsDeny: map[string]bool{}}
+
+// LoadConfig seeds settings from .npmrc and then reads goose.toml from the
+// current directory, if present, letting goose.toml override anything
+// .npmrc also set. A missing goose.toml just means "no further overrides",
+// not an error.
+func LoadConfig() error {
+	npmrc := loadNpmrc()
+	cfg := &Config{
+		ScriptsAllow:    map[string]bool{},
+		ScriptsDeny:     map[string]bool{},
+		DefaultRegistry: npmrc.defaultRegistry,
+		ScopeRegistries: npmrc.scopeRegistries,
+		RegistryTokens:  npmrc.authTokens,
+	}
+
+	f, err := os.Open(ConfigName)
+	if os.IsNotExist(err) {
+		config = cfg
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open %s: %w", ConfigName, err)
+	}
+	defer f.Close()
+
+	section := ""
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.Trim(strings.TrimSpace(parts[1]), `"'`)
+
+		switch {
+		case section == "" && key == "ignore-scripts":
+			cfg.IgnoreScripts, _ = strconv.ParseBool(value)
+		case section == "scripts" && key == "allow":
+			cfg.ScriptsAllow = parseStringSet(value)
+		case section == "scripts" && key == "deny":
+			cfg.ScriptsDeny = parseStringSet(value)
+		case section == "registry" && key == "default":
+			cfg.DefaultRegistry = value
+		case section == "registry.scopes":
+			cfg.ScopeRegistries[key] = value
+		case section == "registry.tokens":
+			cfg.RegistryTokens[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("parse %s: %w", ConfigName, err)
+	}
+
+	config = cfg
+	return nil
+}
+
+// parseStringSet parses a minimal TOML string array like
+// ["esbuild", "node-gyp"] into a set.
+func parseStringSet(value string) map[string]bool {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "[")
+	value = strings.TrimSuffix(strings.TrimSpace(value), "]")
+
+	out := map[string]bool{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.Trim(strings.TrimSpace(part), `"'`)
+		if part != "" {
+			out[part] = true
+		}
+	}
+	return out
+}
+
+// scriptsAllowedFor reports whether a package's lifecycle scripts should
+// run, combining the global --ignore-scripts flag/config default with
+// the per-package goose.toml allow/deny lists.
+func scriptsAllowedFor(name string) bool {
+	if config.ScriptsDeny[name] {
+		return false
+	}
+	if ignoreScripts || config.IgnoreScripts {
+		return config.ScriptsAllow[name]
+	}
+	return true
+}
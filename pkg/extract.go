@@ -0,0 +1,204 @@
+package pkg
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxExtractEntries and maxExtractBytes guard against tar bombs: a small,
+// legitimately-sized download that decompresses into an enormous number
+// of files or an enormous amount of data.
+const (
+	maxExtractEntries = 100_000
+	maxExtractBytes   = 1 << 30 // 1 GiB per package
+)
+
+// downloadAndExtract fetches the tarball at url, verifies it against
+// integrity, and materializes it at dest (a pkgs/<name>/<version>
+// directory). Every regular file is written into the content-addressable
+// store first and then hardlinked into dest, so identical files across
+// packages and versions are only ever stored once.
+//
+// Tar entries are treated as hostile input: names are resolved with
+// safeJoin so no entry can escape dest via ".." or an absolute path, and
+// symlink/hardlink entries have their targets validated the same way.
+//
+// The tarball itself is fetched through defaultRegistry rather than
+// net/http directly, so a private registry's auth token is attached the
+// same way it would be for a metadata request.
+//
+// dest is removed on any failure, including an integrity mismatch: the
+// directory's mere presence is install.go's only signal that a package
+// doesn't need downloading again, so a partially or fully written but
+// unverified tree must never be left behind for a later install to
+// silently reuse. The CAS blobs dest's files were hardlinked from are
+// left alone — they're shared with every other reference to the same
+// content, and `goose store prune` already reclaims anything orphaned.
+func downloadAndExtract(ctx context.Context, url, store, dest, integrity string) (err error) {
+	rc, err := defaultRegistry.Tarball(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(dest)
+		}
+	}()
+
+	hasher := sha512.New()
+	body := io.TeeReader(rc, hasher)
+
+	files, err := extractTarGz(store, dest, body)
+	if err != nil {
+		return err
+	}
+
+	// Drain anything left unread so the hash covers the full tarball, then
+	// verify it against the registry-provided integrity before trusting
+	// the files we just wrote.
+	if _, err = io.Copy(io.Discard, body); err != nil {
+		return fmt.Errorf("drain tarball: %w", err)
+	}
+	if integrity != "" {
+		if err = verifyIntegrity(hasher.Sum(nil), integrity); err != nil {
+			return fmt.Errorf("verify integrity of %s: %w", url, err)
+		}
+	}
+
+	if err = writeManifest(dest, files); err != nil {
+		return err
+	}
+	return nil
+}
+
+// extractTarGz reads a gzip-compressed tar stream and materializes it at
+// dest the same way downloadAndExtract does (CAS-backed, with the same
+// path-traversal and tar-bomb protections), returning a map of relative
+// path to sha256 for every regular file written. Split out from
+// downloadAndExtract so it can be exercised directly against a crafted
+// in-memory tarball.
+func extractTarGz(store, dest string, r io.Reader) (map[string]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("gzip reader: %w", err)
+	}
+	defer gz.Close()
+
+	files := map[string]string{}
+	entries := 0
+	var totalBytes int64
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read tar: %w", err)
+		}
+
+		if !strings.HasPrefix(hdr.Name, "package/") {
+			continue
+		}
+
+		entries++
+		if entries > maxExtractEntries {
+			return nil, fmt.Errorf("tarball has more than %d entries, aborting", maxExtractEntries)
+		}
+		if hdr.Size < 0 || totalBytes+hdr.Size > maxExtractBytes {
+			return nil, fmt.Errorf("tarball exceeds %d byte extraction limit", maxExtractBytes)
+		}
+		totalBytes += hdr.Size
+
+		relPath := strings.TrimPrefix(hdr.Name, "package/")
+		target, err := safeJoin(dest, relPath)
+		if err != nil {
+			return nil, fmt.Errorf("tar entry %q: %w", hdr.Name, err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, hdr.FileInfo().Mode()); err != nil {
+				return nil, fmt.Errorf("mkdir %s: %w", target, err)
+			}
+
+		case tar.TypeReg:
+			sum, err := writeBlob(store, io.LimitReader(tr, hdr.Size))
+			if err != nil {
+				return nil, fmt.Errorf("store blob for %s: %w", relPath, err)
+			}
+			if err := linkBlob(casPath(store, sum), target); err != nil {
+				return nil, fmt.Errorf("link %s: %w", target, err)
+			}
+			files[relPath] = sum
+
+		case tar.TypeSymlink:
+			if filepath.IsAbs(hdr.Linkname) {
+				return nil, fmt.Errorf("symlink %s -> %s escapes destination: absolute link target", relPath, hdr.Linkname)
+			}
+			resolved := filepath.Join(filepath.Dir(target), hdr.Linkname)
+			if !withinDest(dest, resolved) {
+				return nil, fmt.Errorf("symlink %s -> %s escapes destination", relPath, hdr.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return nil, fmt.Errorf("mkdir parent %s: %w", target, err)
+			}
+			_ = os.Remove(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return nil, fmt.Errorf("symlink %s -> %s: %w", target, hdr.Linkname, err)
+			}
+
+		case tar.TypeLink:
+			linkRel := strings.TrimPrefix(hdr.Linkname, "package/")
+			linkTarget, err := safeJoin(dest, linkRel)
+			if err != nil {
+				return nil, fmt.Errorf("hardlink entry %q: %w", hdr.Name, err)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return nil, fmt.Errorf("mkdir parent %s: %w", target, err)
+			}
+			_ = os.Remove(target)
+			if err := os.Link(linkTarget, target); err != nil {
+				return nil, fmt.Errorf("hardlink %s -> %s: %w", target, linkTarget, err)
+			}
+
+		default:
+			return nil, fmt.Errorf("unsupported tar entry type %q for %s", hdr.Typeflag, relPath)
+		}
+	}
+
+	return files, nil
+}
+
+// safeJoin joins dest and rel, rejecting any result that would escape
+// dest — via "..", an absolute path, or (on Windows) a drive-qualified
+// path.
+func safeJoin(dest, rel string) (string, error) {
+	if filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" {
+		return "", fmt.Errorf("escapes destination: absolute path %q", rel)
+	}
+
+	joined := filepath.Join(dest, rel)
+	if !withinDest(dest, joined) {
+		return "", fmt.Errorf("escapes destination: %q", rel)
+	}
+	return joined, nil
+}
+
+// withinDest reports whether path is dest itself or a descendant of it,
+// after cleaning both.
+func withinDest(dest, path string) bool {
+	cleanDest := filepath.Clean(dest)
+	cleanPath := filepath.Clean(path)
+	return cleanPath == cleanDest || strings.HasPrefix(cleanPath, cleanDest+string(filepath.Separator))
+}
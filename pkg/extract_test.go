@@ -0,0 +1,168 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarEntry describes one entry to bake into a crafted test tarball.
+type tarEntry struct {
+	name     string // tar header name, e.g. "package/../../etc/passwd"
+	typeflag byte
+	linkname string
+	body     string
+}
+
+func buildTarGz(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Linkname: e.linkname,
+			Size:     int64(len(e.body)),
+			Mode:     0644,
+		}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("write header %s: %v", e.name, err)
+		}
+		if e.body != "" {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatalf("write body %s: %v", e.name, err)
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar writer: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("close gzip writer: %v", err)
+	}
+	return &buf
+}
+
+func TestExtractTarGz(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		wantErr bool
+	}{
+		{
+			name: "regular package extracts cleanly",
+			entries: []tarEntry{
+				{name: "package/package.json", typeflag: tar.TypeReg, body: `{"name":"x"}`},
+				{name: "package/lib/index.js", typeflag: tar.TypeReg, body: "module.exports = {}"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "relative path traversal is rejected",
+			entries: []tarEntry{
+				{name: "package/../../etc/passwd", typeflag: tar.TypeReg, body: "pwned"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "symlink escaping dest is rejected",
+			entries: []tarEntry{
+				{name: "package/evil", typeflag: tar.TypeSymlink, linkname: "../../../etc/passwd"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "symlink with absolute target is rejected",
+			entries: []tarEntry{
+				{name: "package/evil", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "symlink within dest is allowed",
+			entries: []tarEntry{
+				{name: "package/real.js", typeflag: tar.TypeReg, body: "ok"},
+				{name: "package/alias.js", typeflag: tar.TypeSymlink, linkname: "real.js"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "hardlink escaping dest is rejected",
+			entries: []tarEntry{
+				{name: "package/evil", typeflag: tar.TypeLink, linkname: "/etc/passwd"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "hardlink within dest is allowed",
+			entries: []tarEntry{
+				{name: "package/real.js", typeflag: tar.TypeReg, body: "ok"},
+				{name: "package/alias.js", typeflag: tar.TypeLink, linkname: "package/real.js"},
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := t.TempDir()
+			dest := filepath.Join(t.TempDir(), "pkg")
+
+			_, err := extractTarGz(store, dest, buildTarGz(t, tt.entries))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractTarGz() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if _, statErr := os.Stat(dest); statErr != nil {
+					t.Fatalf("expected dest %s to exist: %v", dest, statErr)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractTarGzEntryCap(t *testing.T) {
+	entries := make([]tarEntry, maxExtractEntries+1)
+	for i := range entries {
+		entries[i] = tarEntry{name: filepath.Join("package", "f", string(rune('a'+i%26))), typeflag: tar.TypeReg}
+	}
+
+	store := t.TempDir()
+	dest := filepath.Join(t.TempDir(), "pkg")
+
+	if _, err := extractTarGz(store, dest, buildTarGz(t, entries)); err == nil {
+		t.Fatal("expected an error for a tarball exceeding the entry cap")
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	dest := "/cache/pkgs/foo/1.0.0"
+
+	tests := []struct {
+		rel     string
+		wantErr bool
+	}{
+		{"index.js", false},
+		{"lib/index.js", false},
+		{"../../../etc/passwd", true},
+		{"/etc/passwd", true},
+	}
+
+	for _, tt := range tests {
+		_, err := safeJoin(dest, tt.rel)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("safeJoin(%q, %q) error = %v, wantErr %v", dest, tt.rel, err, tt.wantErr)
+		}
+	}
+}
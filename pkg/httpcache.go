@@ -0,0 +1,136 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// httpCacheEntry is what's persisted on disk per cached metadata response:
+// the validators needed for a conditional GET, plus the body itself so a
+// 304 reply can be served straight from disk without touching the network
+// again.
+type httpCacheEntry struct {
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"lastModified,omitempty"`
+	Body         json.RawMessage `json:"body"`
+}
+
+func httpCacheDir() (string, error) {
+	store, err := storeRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(store, "http-cache"), nil
+}
+
+func httpCachePath(url string) (string, error) {
+	dir, err := httpCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+func loadHTTPCacheEntry(url string) (*httpCacheEntry, error) {
+	path, err := httpCachePath(url)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry httpCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// A corrupt cache entry is a cache miss, not a fatal error.
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+func saveHTTPCacheEntry(url string, entry *httpCacheEntry) error {
+	path, err := httpCachePath(url)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("mkdir http cache: %w", err)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal http cache entry: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// fetchMetaHTTP performs a conditional GET against url, attaching token as
+// a bearer credential when set. When an on-disk cache entry exists, its
+// ETag/Last-Modified validators are sent along so an unchanged package
+// costs the registry a 304 instead of a full metadata payload; goose then
+// decodes the cached body instead of re-fetching it.
+func fetchMetaHTTP(ctx context.Context, url, token string) (*PackageMeta, error) {
+	cached, _ := loadHTTPCacheEntry(url) // a cache-read failure just means "no cache"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build metadata request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch metadata: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		var meta PackageMeta
+		if err := json.Unmarshal(cached.Body, &meta); err != nil {
+			return nil, fmt.Errorf("parse cached metadata for %s: %w", url, err)
+		}
+		return &meta, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read metadata: %w", err)
+	}
+
+	var meta PackageMeta
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return nil, fmt.Errorf("decode metadata: %w", err)
+	}
+
+	if etag, lastMod := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"); etag != "" || lastMod != "" {
+		_ = saveHTTPCacheEntry(url, &httpCacheEntry{ETag: etag, LastModified: lastMod, Body: body})
+	}
+
+	return &meta, nil
+}
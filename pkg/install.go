@@ -0,0 +1,355 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// resolverPoolSize and downloaderPoolSize bound how many registry
+// round-trips and tarball downloads run concurrently, so a large
+// dependency graph doesn't open hundreds of sockets at once.
+const (
+	resolverPoolSize   = 8
+	downloaderPoolSize = 8
+)
+
+// virtualStoreDir is the project-local directory holding one entry per
+// resolved "name@version", pnpm-style, so two dependents of the same
+// project can depend on different, non-hoisted versions of the same
+// package without clobbering each other.
+const virtualStoreDir = ".goose"
+
+// Install resolves and installs pkg and its full dependency tree,
+// fanning the work out across a resolver pool and a downloader pool.
+func Install(pkg PackageInfo) error {
+	return InstallContext(context.Background(), pkg)
+}
+
+// InstallContext is Install with an explicit context; cancelling ctx
+// (e.g. on Ctrl-C) aborts in-flight registry and tarball requests.
+func InstallContext(ctx context.Context, pkg PackageInfo) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	store, err := storeRoot()
+	if err != nil {
+		return err
+	}
+
+	// In frozen mode the lockfile is authoritative and no solving is
+	// needed; otherwise run the full graph resolution pass up front so a
+	// conflict is reported before anything is downloaded.
+	var plan Plan
+	if !frozenMode {
+		plan, err = Resolve(ctx, []PackageInfo{pkg}, registryFetcher{})
+		if err != nil {
+			return fmt.Errorf("resolve dependency graph: %w", err)
+		}
+	}
+
+	in := &installer{
+		ctx:         ctx,
+		cancel:      cancel,
+		store:       store,
+		plan:        plan,
+		resolveSem:  make(chan struct{}, resolverPoolSize),
+		downloadSem: make(chan struct{}, downloaderPoolSize),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go in.install(&wg, pkg, true, "", nil)
+	wg.Wait()
+
+	return in.firstErr
+}
+
+// installer carries the state shared by every goroutine spawned for a
+// single Install call: the semaphores bounding concurrency, the set of
+// already-installed packages, and the first error seen (which cancels
+// everything still in flight).
+type installer struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	store       string
+	plan        Plan // exact versions chosen by the Resolve pass, if any
+	resolveSem  chan struct{}
+	downloadSem chan struct{}
+
+	installed sync.Map // resolved "name@version" -> struct{}
+
+	mu       sync.Mutex
+	firstErr error
+}
+
+func (in *installer) fail(err error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.firstErr == nil {
+		in.firstErr = err
+		in.cancel()
+	}
+}
+
+func (in *installer) failed() bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.firstErr != nil
+}
+
+// childEntry is how a dependency reports its resolved name/version back
+// to the parent that requested it, so the parent can symlink it into its
+// own virtual node_modules once resolution is known.
+type childEntry struct {
+	name    string
+	version string
+}
+
+// depLinks collects childEntry values from concurrently-running goroutines.
+type depLinks struct {
+	mu      sync.Mutex
+	entries []childEntry
+}
+
+func (l *depLinks) add(e childEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, e)
+}
+
+// virtualPkgDir is the project-local, per-version directory a resolved
+// package lives in: node_modules/.goose/<name>@<version>/node_modules/<name>.
+// It's a pure function of name+version so parents can compute a
+// dependency's path without waiting for that dependency to finish
+// installing.
+func virtualPkgDir(name, version string) string {
+	return filepath.Join(virtualInnerNodeModules(name, version), name)
+}
+
+func virtualInnerNodeModules(name, version string) string {
+	return filepath.Join("node_modules", virtualStoreDir, name+"@"+version, "node_modules")
+}
+
+// install resolves pkg, materializes it into the store-backed virtual
+// node_modules tree, and recurses into its dependencies in parallel.
+// wg.Done is called exactly once. parentKey identifies the dependency edge
+// pkg was reached through ("" for a root install, or "<name>@<version>" of
+// the dependent that required it), which is how the Plan's per-edge
+// resolution is looked up. If parentLinks is non-nil, the resolved
+// name/version is reported back to the caller so it can link this package
+// into its own virtual node_modules.
+func (in *installer) install(wg *sync.WaitGroup, pkg PackageInfo, isRoot bool, parentKey string, parentLinks *depLinks) {
+	defer wg.Done()
+
+	if in.ctx.Err() != nil {
+		return
+	}
+
+	// If the Resolve pass already picked an exact version for this
+	// dependency edge, use it instead of pkg's own (possibly loose) range.
+	// Different parents of the same package name are independent edges, so
+	// they may legitimately resolve to different versions.
+	reqPkg := pkg
+	if version, ok := in.plan.Packages[edgeKey(parentKey, pkg.Name)]; ok {
+		reqPkg.Version = version
+	}
+
+	in.resolveSem <- struct{}{}
+	fmt.Printf("Resolving %s@%s...\n", reqPkg.Name, reqPkg.Version)
+	res := resolveOnce(in.ctx, reqPkg)
+	<-in.resolveSem
+
+	if res.err != nil {
+		in.fail(fmt.Errorf("resolve %s@%s: %w", reqPkg.Name, reqPkg.Version, res.err))
+		return
+	}
+
+	// The lockfile is keyed by the requested range, so when the Resolve pass
+	// pinned reqPkg to an exact version, record the resolution under pkg's
+	// own spec too.
+	if reqPkg.Version != pkg.Version {
+		lockMu.Lock()
+		lockState.Packages[lockKey(pkg)] = LockedPackage{Version: res.version, Resolved: res.tarball, Integrity: res.integrity, Dependencies: res.deps}
+		lockMu.Unlock()
+	}
+
+	if parentLinks != nil {
+		parentLinks.add(childEntry{name: pkg.Name, version: res.version})
+	}
+
+	resolvedKey := pkg.Name + "@" + res.version
+	vPkgDir := virtualPkgDir(pkg.Name, res.version)
+
+	if isRoot {
+		linkPath := filepath.Join("node_modules", pkg.Name)
+		_ = os.RemoveAll(linkPath)
+		if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
+			in.fail(fmt.Errorf("create parent dir for %s: %w", linkPath, err))
+			return
+		}
+		target, err := filepath.Rel(filepath.Dir(linkPath), vPkgDir)
+		if err != nil {
+			in.fail(fmt.Errorf("relativize %s: %w", vPkgDir, err))
+			return
+		}
+		if err := os.Symlink(target, linkPath); err != nil {
+			in.fail(fmt.Errorf("symlink %s -> %s: %w", linkPath, vPkgDir, err))
+			return
+		}
+	}
+
+	if _, already := in.installed.LoadOrStore(resolvedKey, struct{}{}); already {
+		return
+	}
+
+	resolvedPkg := PackageInfo{Name: pkg.Name, Version: res.version}
+	storeDir := pkgVersionDir(in.store, resolvedPkg)
+
+	if _, statErr := os.Stat(storeDir); os.IsNotExist(statErr) {
+		in.downloadSem <- struct{}{}
+		fmt.Printf("Downloading %s...\n", res.tarball)
+		err := downloadAndExtract(in.ctx, res.tarball, in.store, storeDir, res.integrity)
+		<-in.downloadSem
+		if err != nil {
+			in.fail(fmt.Errorf("download and extract %s: %w", resolvedKey, err))
+			return
+		}
+	}
+
+	pj, err := readPackageJSON(storeDir)
+	if err != nil {
+		in.fail(fmt.Errorf("read package.json for %s: %w", resolvedKey, err))
+		return
+	}
+	needsPrivateCopy := len(pj.Scripts) > 0 || len(pj.binEntries(pkg.Name)) > 0
+
+	// vPkgDir only gets its own copy of storeDir when pkg can mutate files
+	// in place: storeDir is the single, content-addressable-backed
+	// directory shared by every project on the machine that happens to
+	// need this exact name@version (see pkgVersionDir), and a lifecycle
+	// script or linkBin's chmod writing there would corrupt that shared
+	// copy for every other reference to it. Everything else is symlinked
+	// straight to storeDir, so the CAS's dedup promise — two projects never
+	// duplicate package contents on disk — still holds for the common case
+	// of a package with no scripts or bin entries. A pre-existing vPkgDir
+	// means a previous install already did this work, so it's left
+	// untouched rather than redone every run.
+	freshCopy := false
+	if _, err := os.Stat(vPkgDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(vPkgDir), 0755); err != nil {
+			in.fail(fmt.Errorf("create virtual store dir for %s: %w", resolvedKey, err))
+			return
+		}
+		if needsPrivateCopy {
+			if err := copyTree(storeDir, vPkgDir); err != nil {
+				in.fail(fmt.Errorf("copy %s into virtual store: %w", resolvedKey, err))
+				return
+			}
+		} else if err := os.Symlink(storeDir, vPkgDir); err != nil {
+			in.fail(fmt.Errorf("link %s into virtual store: %w", resolvedKey, err))
+			return
+		}
+		freshCopy = true
+	} else if err != nil {
+		in.fail(fmt.Errorf("stat virtual store dir for %s: %w", resolvedKey, err))
+		return
+	}
+
+	fmt.Printf("Linked %s@%s\n", pkg.Name, res.version)
+
+	var depWg sync.WaitGroup
+	myLinks := &depLinks{}
+	for dep, depVer := range res.deps {
+		depWg.Add(1)
+		go in.install(&depWg, PackageInfo{Name: dep, Version: depVer}, false, resolvedKey, myLinks)
+	}
+	depWg.Wait()
+
+	if in.failed() {
+		return
+	}
+
+	innerNM := virtualInnerNodeModules(pkg.Name, res.version)
+	for _, dep := range myLinks.entries {
+		depLink := filepath.Join(innerNM, dep.name)
+		_ = os.RemoveAll(depLink)
+		if err := os.MkdirAll(filepath.Dir(depLink), 0755); err != nil {
+			in.fail(fmt.Errorf("create node_modules for %s: %w", resolvedKey, err))
+			return
+		}
+		target, err := filepath.Rel(filepath.Dir(depLink), virtualPkgDir(dep.name, dep.version))
+		if err != nil {
+			in.fail(fmt.Errorf("relativize link for %s: %w", dep.name, err))
+			return
+		}
+		if err := os.Symlink(target, depLink); err != nil {
+			in.fail(fmt.Errorf("link dep %s into %s: %w", dep.name, resolvedKey, err))
+			return
+		}
+	}
+
+	if !freshCopy || !needsPrivateCopy {
+		return
+	}
+
+	if err := linkBin(pj, resolvedPkg, vPkgDir); err != nil {
+		in.fail(fmt.Errorf("link bin for %s: %w", resolvedKey, err))
+		return
+	}
+	if err := runLifecycleScripts(in.ctx, resolvedPkg, vPkgDir, scriptsAllowedFor(pkg.Name)); err != nil {
+		in.fail(fmt.Errorf("lifecycle scripts for %s: %w", resolvedKey, err))
+		return
+	}
+}
+
+// resolveResult is the outcome of resolving a single name@range spec,
+// shared between every caller that raced to resolve the same one.
+type resolveResult struct {
+	version   string
+	tarball   string
+	integrity string
+	deps      map[string]string
+	err       error
+}
+
+var (
+	inFlightMu  sync.Mutex
+	inFlight    = map[string]*sync.WaitGroup{}
+	inFlightRes = map[string]*resolveResult{}
+)
+
+// resolveOnce deduplicates concurrent resolves of the same name@range, so
+// two branches of the dependency graph that need the same spec share one
+// registry round-trip instead of issuing it twice.
+func resolveOnce(ctx context.Context, pkg PackageInfo) *resolveResult {
+	key := lockKey(pkg)
+
+	inFlightMu.Lock()
+	if wg, ok := inFlight[key]; ok {
+		inFlightMu.Unlock()
+		wg.Wait()
+		inFlightMu.Lock()
+		res := inFlightRes[key]
+		inFlightMu.Unlock()
+		return res
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	inFlight[key] = wg
+	inFlightMu.Unlock()
+
+	version, tarball, integrity, deps, err := resolvePackage(ctx, pkg)
+	res := &resolveResult{version: version, tarball: tarball, integrity: integrity, deps: deps, err: err}
+
+	inFlightMu.Lock()
+	inFlightRes[key] = res
+	delete(inFlight, key)
+	inFlightMu.Unlock()
+	wg.Done()
+
+	return res
+}
@@ -0,0 +1,110 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// LockfileName is the name of the lockfile goose reads and writes in the
+// current working directory, analogous to package-lock.json or go.sum.
+const LockfileName = "goose.lock"
+
+// LockedPackage is a single resolved entry in the lockfile: an exact
+// version, where it came from, and enough information to verify the
+// tarball without talking to the registry again.
+type LockedPackage struct {
+	Version      string            `json:"version"`
+	Resolved     string            `json:"resolved"`
+	Integrity    string            `json:"integrity"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+// Lockfile is the on-disk representation of goose.lock. Packages is keyed
+// by "name@range" (the requested spec, not the resolved version), so the
+// same range always maps back to the same resolution.
+type Lockfile struct {
+	Packages map[string]LockedPackage `json:"packages"`
+}
+
+var (
+	lockState  *Lockfile
+	lockMu     sync.Mutex // guards lockState.Packages against concurrent resolvers
+	frozenMode bool
+	updateOnly string
+)
+
+// SetFrozen enables --frozen mode: resolvePackage will refuse to contact
+// the registry and will fail if a requested package has no lockfile entry.
+func SetFrozen(frozen bool) {
+	frozenMode = frozen
+}
+
+// SetUpdateTarget marks a single package name for forced re-resolution
+// (used by `goose update <pkg>`), bypassing its existing lockfile entry.
+func SetUpdateTarget(name string) {
+	updateOnly = name
+}
+
+// LoadLock reads goose.lock from the current directory into package state.
+// A missing lockfile is not an error in normal mode (it will be created),
+// but is an error when frozenMode is set.
+func LoadLock() error {
+	if frozenMode {
+		if _, err := os.Stat(LockfileName); os.IsNotExist(err) {
+			return fmt.Errorf("%s not found (required in frozen mode)", LockfileName)
+		}
+	}
+	lf, err := loadLockfile(LockfileName)
+	if err != nil {
+		return err
+	}
+	lockState = lf
+	return nil
+}
+
+// SaveLock writes the in-memory lockfile state back to goose.lock.
+func SaveLock() error {
+	lockMu.Lock()
+	defer lockMu.Unlock()
+	if lockState == nil {
+		lockState = &Lockfile{Packages: map[string]LockedPackage{}}
+	}
+	return lockState.save(LockfileName)
+}
+
+func loadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Lockfile{Packages: map[string]LockedPackage{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read lockfile: %w", err)
+	}
+
+	var lf Lockfile
+	if err := json.Unmarshal(data, &lf); err != nil {
+		return nil, fmt.Errorf("parse lockfile: %w", err)
+	}
+	if lf.Packages == nil {
+		lf.Packages = map[string]LockedPackage{}
+	}
+	return &lf, nil
+}
+
+func (l *Lockfile) save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal lockfile: %w", err)
+	}
+	data = append(data, '\n')
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write lockfile: %w", err)
+	}
+	return nil
+}
+
+func lockKey(pkg PackageInfo) string {
+	return pkg.Name + "@" + pkg.Version
+}
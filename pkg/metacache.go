@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"container/list"
+	"sync"
+)
+
+// metaCacheSize bounds how many distinct packages' registry metadata are
+// kept in memory at once; graphs larger than this just see more cache
+// misses rather than unbounded growth.
+const metaCacheSize = 256
+
+type metaCacheEntry struct {
+	name string
+	meta *PackageMeta
+}
+
+// lruCache is a small in-memory, concurrency-safe LRU of registry
+// metadata responses keyed by registry base URL and package name (see
+// npmRegistry.Metadata), so repeated lookups of the
+// same package while walking a dependency graph don't re-hit the network.
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+var metaCache = newLRUCache(metaCacheSize)
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache) get(name string) (*PackageMeta, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[name]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*metaCacheEntry).meta, true
+}
+
+func (c *lruCache) put(name string, meta *PackageMeta) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[name]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*metaCacheEntry).meta = meta
+		return
+	}
+
+	el := c.ll.PushFront(&metaCacheEntry{name: name, meta: meta})
+	c.items[name] = el
+
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*metaCacheEntry).name)
+		}
+	}
+}
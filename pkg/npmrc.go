@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// npmrcConfig is the subset of .npmrc goose understands: the default
+// registry, per-scope registry overrides, and per-host auth tokens. It
+// mirrors the handful of keys real npm clients read from the same file.
+type npmrcConfig struct {
+	defaultRegistry string
+	scopeRegistries map[string]string
+	authTokens      map[string]string
+}
+
+// loadNpmrc merges $HOME/.npmrc and ./.npmrc, in that order, so a
+// project's .npmrc can override a user's global one. A missing or
+// unreadable file just means "nothing configured there", not an error.
+func loadNpmrc() *npmrcConfig {
+	cfg := &npmrcConfig{scopeRegistries: map[string]string{}, authTokens: map[string]string{}}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		cfg.mergeFile(filepath.Join(home, ".npmrc"))
+	}
+	cfg.mergeFile(".npmrc")
+
+	return cfg
+}
+
+func (c *npmrcConfig) mergeFile(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.Trim(strings.TrimSpace(parts[1]), `"'`)
+
+		switch {
+		case strings.HasPrefix(key, "@") && strings.Contains(key, ":registry"):
+			// "@scope:registry=https://npm.mycorp.com"
+			scope := key[:strings.Index(key, ":registry")]
+			c.scopeRegistries[scope] = value
+		case strings.HasPrefix(key, "//") && strings.HasSuffix(key, ":_authToken"):
+			// "//npm.mycorp.com/:_authToken=..."
+			host := strings.TrimSuffix(key, ":_authToken")
+			host = strings.TrimPrefix(host, "//")
+			host = strings.TrimSuffix(host, "/")
+			c.authTokens[host] = value
+		case key == "registry":
+			c.defaultRegistry = value
+		}
+	}
+}
@@ -1,15 +1,10 @@
 package pkg
 
 import (
-	"archive/tar"
-	"compress/gzip"
-	"encoding/json"
+	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 
 	semver "github.com/Masterminds/semver/v3"
@@ -19,7 +14,8 @@ type PackageMeta struct {
 	DistTags map[string]string `json:"dist-tags"`
 	Versions map[string]struct {
 		Dist struct {
-			Tarball string `json:"tarball"`
+			Tarball   string `json:"tarball"`
+			Integrity string `json:"integrity"`
 		} `json:"dist"`
 		Dependencies map[string]string `json:"dependencies"`
 	} `json:"versions"`
@@ -30,8 +26,6 @@ type PackageInfo struct {
 	Version string
 }
 
-var installed = make(map[string]bool)
-
 func Parse(arg string) (PackageInfo, error) {
 	if arg == "" {
 		return PackageInfo{}, errors.New("package name cannot be empty")
@@ -65,71 +59,28 @@ func Parse(arg string) (PackageInfo, error) {
 	return PackageInfo{Name: arg, Version: "latest"}, nil
 }
 
-func Install(pkg PackageInfo) error {
-	key := pkg.Name + "@" + pkg.Version
-	if installed[key] {
-		return nil
-	}
-	installed[key] = true
-
-	fmt.Printf("Resolving %s@%s...\n", pkg.Name, pkg.Version)
-	resolved, tarball, deps, err := resolvePackage(pkg)
-	if err != nil {
-		return fmt.Errorf("resolve %s: %w", key, err)
-	}
-
-	resolvedPkg := PackageInfo{Name: pkg.Name, Version: resolved}
-	cachePath, err := getCachePath(resolvedPkg)
-	if err != nil {
-		return fmt.Errorf("get cache path for %s: %w", key, err)
-	}
-
-	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
-		fmt.Printf("Downloading %s...\n", tarball)
-		if err := downloadAndExtract(tarball, cachePath); err != nil {
-			return fmt.Errorf("download and extract %s: %w", key, err)
+func resolvePackage(ctx context.Context, pkg PackageInfo) (resolved string, tarball string, integrity string, deps map[string]string, err error) {
+	if lockState == nil {
+		if err := LoadLock(); err != nil {
+			return "", "", "", nil, err
 		}
 	}
 
-	linkPath := filepath.Join("node_modules", pkg.Name)
-	_ = os.RemoveAll(linkPath)
-	if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
-		return fmt.Errorf("create parent dir for %s: %w", linkPath, err)
-	}
-	if err := os.Symlink(cachePath, linkPath); err != nil {
-		return fmt.Errorf("symlink %s -> %s: %w", cachePath, linkPath, err)
-	}
-
-	fmt.Printf("Linked %s@%s\n", pkg.Name, resolved)
-
-	for dep, depVer := range deps {
-		if err := Install(PackageInfo{Name: dep, Version: depVer}); err != nil {
-			return fmt.Errorf("install dep %s@%s: %w", dep, depVer, err)
-		}
+	key := lockKey(pkg)
+	lockMu.Lock()
+	entry, ok := lockState.Packages[key]
+	lockMu.Unlock()
+	if ok && pkg.Name != updateOnly {
+		return entry.Version, entry.Resolved, entry.Integrity, entry.Dependencies, nil
 	}
 
-	return nil
-}
-
-func resolvePackage(pkg PackageInfo) (resolved string, tarball string, deps map[string]string, err error) {
-	encoded := pkg.Name
-	if strings.HasPrefix(pkg.Name, "@") {
-		encoded = strings.ReplaceAll(pkg.Name, "/", "%2F")
+	if frozenMode {
+		return "", "", "", nil, fmt.Errorf("no lockfile entry for %s (run `goose install` without --frozen to update %s)", key, LockfileName)
 	}
 
-	resp, err := http.Get("https://registry.npmjs.org/" + encoded)
+	meta, err := registryForName(pkg.Name).Metadata(ctx, pkg.Name)
 	if err != nil {
-		return "", "", nil, fmt.Errorf("fetch metadata: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", "", nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
-	}
-
-	var meta PackageMeta
-	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
-		return "", "", nil, fmt.Errorf("decode metadata: %w", err)
+		return "", "", "", nil, err
 	}
 
 	version := pkg.Version
@@ -137,96 +88,81 @@ func resolvePackage(pkg PackageInfo) (resolved string, tarball string, deps map[
 		version = tag
 	}
 
-	if vinfo, ok := meta.Versions[version]; ok {
-		return version, vinfo.Dist.Tarball, vinfo.Dependencies, nil
-	}
-
-	constraint, err := semver.NewConstraint(version)
-	if err != nil {
-		return "", "", nil, fmt.Errorf("invalid version constraint %q: %w", version, err)
+	var vinfo struct {
+		Dist struct {
+			Tarball   string `json:"tarball"`
+			Integrity string `json:"integrity"`
+		} `json:"dist"`
+		Dependencies map[string]string `json:"dependencies"`
 	}
 
-	var matchedVersion string
-	var matchedSemver *semver.Version
-
-	for v := range meta.Versions {
-		ver, err := semver.NewVersion(v)
+	if v, ok := meta.Versions[version]; ok {
+		vinfo = v
+	} else {
+		constraint, err := semver.NewConstraint(version)
 		if err != nil {
-			continue
+			return "", "", "", nil, fmt.Errorf("invalid version constraint %q: %w", version, err)
 		}
-		if constraint.Check(ver) {
-			if matchedSemver == nil || ver.GreaterThan(matchedSemver) {
-				matchedSemver = ver
-				matchedVersion = v
+
+		var matchedVersion string
+		var matchedSemver *semver.Version
+
+		for v := range meta.Versions {
+			ver, err := semver.NewVersion(v)
+			if err != nil {
+				continue
 			}
+			if constraint.Check(ver) {
+				if matchedSemver == nil || ver.GreaterThan(matchedSemver) {
+					matchedSemver = ver
+					matchedVersion = v
+				}
+			}
+		}
+
+		if matchedVersion == "" {
+			return "", "", "", nil, fmt.Errorf("no matching version found for %s@%s", pkg.Name, pkg.Version)
 		}
+
+		version = matchedVersion
+		vinfo = meta.Versions[matchedVersion]
 	}
 
-	if matchedVersion == "" {
-		return "", "", nil, fmt.Errorf("no matching version found for %s@%s", pkg.Name, pkg.Version)
+	lockMu.Lock()
+	lockState.Packages[key] = LockedPackage{
+		Version:      version,
+		Resolved:     vinfo.Dist.Tarball,
+		Integrity:    vinfo.Dist.Integrity,
+		Dependencies: vinfo.Dependencies,
 	}
+	lockMu.Unlock()
 
-	vinfo := meta.Versions[matchedVersion]
-	return matchedVersion, vinfo.Dist.Tarball, vinfo.Dependencies, nil
+	return version, vinfo.Dist.Tarball, vinfo.Dist.Integrity, vinfo.Dependencies, nil
 }
 
-func getCachePath(pkg PackageInfo) (string, error) {
-	cacheDir, err := os.UserCacheDir()
-	if err != nil {
-		return "", fmt.Errorf("get user cache dir: %w", err)
+// encodeScopedName percent-encodes the "/" in a scoped package name (e.g.
+// "@org/pkg" -> "@org%2Fpkg"), which is how the npm registry expects scoped
+// package names in metadata request paths.
+func encodeScopedName(name string) string {
+	if strings.HasPrefix(name, "@") {
+		return strings.ReplaceAll(name, "/", "%2F")
 	}
-	safe := strings.ReplaceAll(pkg.Name, "/", "_")
-	return filepath.Join(cacheDir, "npm-go", safe, pkg.Version), nil
+	return name
 }
 
-func downloadAndExtract(url, dest string) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("http get: %w", err)
-	}
-	defer resp.Body.Close()
-
-	gz, err := gzip.NewReader(resp.Body)
-	if err != nil {
-		return fmt.Errorf("gzip reader: %w", err)
+// verifyIntegrity checks a computed SHA-512 digest against an npm-style
+// "sha512-<base64>" integrity string, as found in dist.integrity.
+func verifyIntegrity(sum []byte, integrity string) error {
+	if !strings.HasPrefix(integrity, "sha512-") {
+		// Unknown/unsupported algorithm (e.g. legacy sha1-); nothing to
+		// compare against.
+		return nil
 	}
-	defer gz.Close()
+	want := strings.TrimPrefix(integrity, "sha512-")
 
-	tr := tar.NewReader(gz)
-	for {
-		hdr, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("read tar: %w", err)
-		}
-
-		if !strings.HasPrefix(hdr.Name, "package/") {
-			continue
-		}
-
-		relPath := strings.TrimPrefix(hdr.Name, "package/")
-		target := filepath.Join(dest, relPath)
-
-		if hdr.FileInfo().IsDir() {
-			if err := os.MkdirAll(target, hdr.FileInfo().Mode()); err != nil {
-				return fmt.Errorf("mkdir %s: %w", target, err)
-			}
-		} else {
-			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
-				return fmt.Errorf("mkdir parent %s: %w", target, err)
-			}
-			f, err := os.Create(target)
-			if err != nil {
-				return fmt.Errorf("create file %s: %w", target, err)
-			}
-			if _, err := io.Copy(f, tr); err != nil {
-				f.Close()
-				return fmt.Errorf("copy to %s: %w", target, err)
-			}
-			f.Close()
-		}
+	got := base64.StdEncoding.EncodeToString(sum)
+	if got != want {
+		return fmt.Errorf("integrity mismatch: expected %s, got sha512-%s", integrity, got)
 	}
 	return nil
 }
@@ -0,0 +1,126 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultRegistryURL is used for any package whose scope (or lack of one)
+// isn't mapped to something else by .npmrc or goose.toml.
+const defaultRegistryURL = "https://registry.npmjs.org/"
+
+// Registry is the seam between goose and wherever packages actually live:
+// the public npm registry, a private Verdaccio/Artifactory/GitHub Packages
+// instance, or an air-gapped mirror. resolvePackage and the installer talk
+// only to this interface, never to net/http directly, so swapping in a
+// different backend (or a fake, in tests) never touches calling code.
+type Registry interface {
+	Metadata(ctx context.Context, name string) (*PackageMeta, error)
+	Tarball(ctx context.Context, url string) (io.ReadCloser, error)
+}
+
+// httpClient is shared by every npmRegistry. Its Transport is left as the
+// zero value, which falls back to http.DefaultTransport and so already
+// honors HTTP_PROXY/HTTPS_PROXY/NO_PROXY via http.ProxyFromEnvironment.
+var httpClient = &http.Client{}
+
+// npmRegistry talks to a single npm-registry-compatible HTTP endpoint.
+type npmRegistry struct {
+	baseURL string
+}
+
+// defaultRegistry is used for tarball downloads, which carry no package
+// name to route by scope. That's fine: auth tokens are resolved per-host
+// (tokenForHost), matching how .npmrc itself scopes tokens to a host
+// rather than to a package scope.
+var defaultRegistry Registry = &npmRegistry{baseURL: defaultRegistryURL}
+
+// registryForName returns the Registry a package's metadata should come
+// from, honoring any "@scope:registry" mapping from .npmrc or goose.toml's
+// [registry.scopes]. Unscoped packages, and scopes with no mapping, fall
+// back to the configured default registry (.npmrc's "registry=", overridden
+// by goose.toml's [registry] default) or, failing that, defaultRegistryURL.
+func registryForName(name string) Registry {
+	base := config.DefaultRegistry
+	if base == "" {
+		base = defaultRegistryURL
+	}
+	if scope := scopeOf(name); scope != "" {
+		if scoped, ok := config.ScopeRegistries[scope]; ok {
+			base = scoped
+		}
+	}
+	return &npmRegistry{baseURL: strings.TrimSuffix(base, "/")}
+}
+
+// scopeOf returns the "@scope" prefix of a package name, or "" if name is
+// unscoped.
+func scopeOf(name string) string {
+	if !strings.HasPrefix(name, "@") {
+		return ""
+	}
+	if slash := strings.Index(name, "/"); slash != -1 {
+		return name[:slash]
+	}
+	return name
+}
+
+// tokenForHost returns the auth token configured for host via .npmrc's
+// "//host/:_authToken" entries or goose.toml's [registry.tokens], or "" if
+// none is configured.
+func tokenForHost(host string) string {
+	return config.RegistryTokens[host]
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// Metadata fetches and decodes a package's registry metadata, preferring
+// (in order) the in-memory LRU, the on-disk ETag/Last-Modified cache, and
+// finally the network.
+func (r *npmRegistry) Metadata(ctx context.Context, name string) (*PackageMeta, error) {
+	cacheKey := r.baseURL + "|" + name
+	if meta, ok := metaCache.get(cacheKey); ok {
+		return meta, nil
+	}
+
+	metaURL := r.baseURL + "/" + encodeScopedName(name)
+	meta, err := fetchMetaHTTP(ctx, metaURL, tokenForHost(hostOf(metaURL)))
+	if err != nil {
+		return nil, err
+	}
+
+	metaCache.put(cacheKey, meta)
+	return meta, nil
+}
+
+// Tarball downloads a package tarball, attaching an Authorization header
+// when the URL's host has a configured token.
+func (r *npmRegistry) Tarball(ctx context.Context, tarballURL string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tarballURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build download request: %w", err)
+	}
+	if token := tokenForHost(hostOf(tarballURL)); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http get: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d fetching %s", resp.StatusCode, tarballURL)
+	}
+	return resp.Body, nil
+}
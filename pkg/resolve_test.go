@@ -0,0 +1,201 @@
+package pkg
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// fakeFetcher is a MetadataFetcher backed by an in-memory map, so Resolve
+// can be exercised without the network.
+type fakeFetcher map[string]*PackageMeta
+
+func (f fakeFetcher) FetchMeta(_ context.Context, name string) (*PackageMeta, error) {
+	meta, ok := f[name]
+	if !ok {
+		return nil, &notFoundError{name: name}
+	}
+	return meta, nil
+}
+
+type notFoundError struct{ name string }
+
+func (e *notFoundError) Error() string { return "package not found: " + e.name }
+
+func versionMeta(deps map[string]string) struct {
+	Dist struct {
+		Tarball   string `json:"tarball"`
+		Integrity string `json:"integrity"`
+	} `json:"dist"`
+	Dependencies map[string]string `json:"dependencies"`
+} {
+	var v struct {
+		Dist struct {
+			Tarball   string `json:"tarball"`
+			Integrity string `json:"integrity"`
+		} `json:"dist"`
+		Dependencies map[string]string `json:"dependencies"`
+	}
+	v.Dependencies = deps
+	return v
+}
+
+func TestResolve(t *testing.T) {
+	t.Run("picks highest version satisfying every requirement", func(t *testing.T) {
+		fetcher := fakeFetcher{
+			"app": {
+				Versions: map[string]struct {
+					Dist struct {
+						Tarball   string `json:"tarball"`
+						Integrity string `json:"integrity"`
+					} `json:"dist"`
+					Dependencies map[string]string `json:"dependencies"`
+				}{
+					"1.0.0": versionMeta(map[string]string{"left-pad": "^1.0.0", "right-pad": "^1.0.0"}),
+				},
+			},
+			"left-pad": {
+				Versions: map[string]struct {
+					Dist struct {
+						Tarball   string `json:"tarball"`
+						Integrity string `json:"integrity"`
+					} `json:"dist"`
+					Dependencies map[string]string `json:"dependencies"`
+				}{
+					"1.0.0": versionMeta(map[string]string{"util": "^1.0.0"}),
+					"1.2.0": versionMeta(map[string]string{"util": "^1.5.0"}),
+				},
+			},
+			"right-pad": {
+				Versions: map[string]struct {
+					Dist struct {
+						Tarball   string `json:"tarball"`
+						Integrity string `json:"integrity"`
+					} `json:"dist"`
+					Dependencies map[string]string `json:"dependencies"`
+				}{
+					"1.0.0": versionMeta(map[string]string{"util": "^1.0.0"}),
+				},
+			},
+			"util": {
+				Versions: map[string]struct {
+					Dist struct {
+						Tarball   string `json:"tarball"`
+						Integrity string `json:"integrity"`
+					} `json:"dist"`
+					Dependencies map[string]string `json:"dependencies"`
+				}{
+					"1.0.0": versionMeta(nil),
+					"1.5.0": versionMeta(nil),
+					"1.9.0": versionMeta(nil),
+				},
+			},
+		}
+
+		plan, err := Resolve(context.Background(), []PackageInfo{{Name: "app", Version: "^1.0.0"}}, fetcher)
+		if err != nil {
+			t.Fatalf("Resolve() error = %v", err)
+		}
+
+		// left-pad@1.2.0 requires util@^1.5.0 and right-pad@1.0.0 requires
+		// util@^1.0.0; both are independent edges, but since each one's own
+		// range is best satisfied by 1.9.0, they converge on it anyway.
+		if got := plan.Packages[edgeKey("left-pad@1.2.0", "util")]; got != "1.9.0" {
+			t.Errorf("left-pad's util = %q, want 1.9.0", got)
+		}
+		if got := plan.Packages[edgeKey("right-pad@1.0.0", "util")]; got != "1.9.0" {
+			t.Errorf("right-pad's util = %q, want 1.9.0", got)
+		}
+		if got := plan.Packages[edgeKey("app@1.0.0", "left-pad")]; got != "1.2.0" {
+			t.Errorf("left-pad = %q, want 1.2.0", got)
+		}
+	})
+
+	t.Run("dependents with incompatible ranges resolve independently", func(t *testing.T) {
+		// app depends directly on react@18, and also on legacy-lib, which
+		// depends on react@17. These don't need to agree: the pnpm-style
+		// virtual store (see virtualPkgDir in install.go) gives each
+		// dependent its own node_modules, so both versions of react can
+		// coexist rather than forcing a single shared one.
+		fetcher := fakeFetcher{
+			"app": {
+				Versions: map[string]struct {
+					Dist struct {
+						Tarball   string `json:"tarball"`
+						Integrity string `json:"integrity"`
+					} `json:"dist"`
+					Dependencies map[string]string `json:"dependencies"`
+				}{
+					"1.0.0": versionMeta(map[string]string{"react": "18.0.0", "legacy-lib": "1.0.0"}),
+				},
+			},
+			"legacy-lib": {
+				Versions: map[string]struct {
+					Dist struct {
+						Tarball   string `json:"tarball"`
+						Integrity string `json:"integrity"`
+					} `json:"dist"`
+					Dependencies map[string]string `json:"dependencies"`
+				}{
+					"1.0.0": versionMeta(map[string]string{"react": "17.0.0"}),
+				},
+			},
+			"react": {
+				Versions: map[string]struct {
+					Dist struct {
+						Tarball   string `json:"tarball"`
+						Integrity string `json:"integrity"`
+					} `json:"dist"`
+					Dependencies map[string]string `json:"dependencies"`
+				}{
+					"17.0.0": versionMeta(nil),
+					"18.0.0": versionMeta(nil),
+				},
+			},
+		}
+
+		plan, err := Resolve(context.Background(), []PackageInfo{{Name: "app", Version: "^1.0.0"}}, fetcher)
+		if err != nil {
+			t.Fatalf("Resolve() error = %v", err)
+		}
+
+		if got := plan.Packages[edgeKey("app@1.0.0", "react")]; got != "18.0.0" {
+			t.Errorf("app's react = %q, want 18.0.0", got)
+		}
+		if got := plan.Packages[edgeKey("legacy-lib@1.0.0", "react")]; got != "17.0.0" {
+			t.Errorf("legacy-lib's react = %q, want 17.0.0", got)
+		}
+	})
+
+	t.Run("two roots naming the same package incompatibly produce a readable conflict", func(t *testing.T) {
+		// Unlike a shared transitive dependency, two roots both naming
+		// "react" directly share a single top-level edge, so an
+		// incompatible pair here is a genuine, unresolvable conflict.
+		fetcher := fakeFetcher{
+			"react": {
+				Versions: map[string]struct {
+					Dist struct {
+						Tarball   string `json:"tarball"`
+						Integrity string `json:"integrity"`
+					} `json:"dist"`
+					Dependencies map[string]string `json:"dependencies"`
+				}{
+					"17.0.0": versionMeta(nil),
+					"18.0.0": versionMeta(nil),
+				},
+			},
+		}
+
+		_, err := Resolve(context.Background(), []PackageInfo{
+			{Name: "react", Version: "18.0.0"},
+			{Name: "react", Version: "17.0.0"},
+		}, fetcher)
+		if err == nil {
+			t.Fatal("Resolve() expected a conflict error, got nil")
+		}
+		if !strings.Contains(err.Error(), "react@18.0.0 required by root") ||
+			!strings.Contains(err.Error(), "react@17.0.0 required by root") {
+			t.Errorf("Resolve() error = %q, want a react version conflict naming both requirers", err.Error())
+		}
+	})
+}
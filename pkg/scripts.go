@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var ignoreScripts bool
+
+// SetIgnoreScripts disables running any package lifecycle scripts,
+// mirroring npm's --ignore-scripts flag. It can still be overridden per
+// package by a goose.toml [scripts] allow entry.
+func SetIgnoreScripts(ignore bool) {
+	ignoreScripts = ignore
+}
+
+// lifecycleHooks is the set of package.json scripts goose runs after
+// extracting a package, in npm's order.
+var lifecycleHooks = []string{"preinstall", "install", "postinstall"}
+
+type packageJSON struct {
+	Scripts map[string]string `json:"scripts"`
+	Bin     json.RawMessage   `json:"bin"`
+}
+
+func readPackageJSON(dir string) (*packageJSON, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if os.IsNotExist(err) {
+		return &packageJSON{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read package.json: %w", err)
+	}
+
+	var pj packageJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return nil, fmt.Errorf("parse package.json: %w", err)
+	}
+	return &pj, nil
+}
+
+// binEntries normalizes package.json's "bin" field, which npm allows to
+// be either a single string (named after the package) or a map of
+// command name to script path.
+func (pj *packageJSON) binEntries(pkgName string) map[string]string {
+	if len(pj.Bin) == 0 {
+		return nil
+	}
+
+	var asString string
+	if err := json.Unmarshal(pj.Bin, &asString); err == nil {
+		name := pkgName
+		if slash := strings.LastIndex(name, "/"); slash != -1 {
+			name = name[slash+1:]
+		}
+		return map[string]string{name: asString}
+	}
+
+	var asMap map[string]string
+	if err := json.Unmarshal(pj.Bin, &asMap); err == nil {
+		return asMap
+	}
+
+	return nil
+}
+
+// linkBin creates executable symlinks under node_modules/.bin for every
+// entry in a package's "bin" field, so sibling packages' lifecycle
+// scripts (and the user, via PATH) can invoke them.
+func linkBin(pj *packageJSON, pkg PackageInfo, dir string) error {
+	entries := pj.binEntries(pkg.Name)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	binDir := filepath.Join("node_modules", ".bin")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", binDir, err)
+	}
+
+	for cmdName, rel := range entries {
+		target, err := filepath.Abs(filepath.Join(dir, rel))
+		if err != nil {
+			return fmt.Errorf("resolve bin target for %s: %w", cmdName, err)
+		}
+		_ = os.Chmod(target, 0755)
+
+		link := filepath.Join(binDir, cmdName)
+		_ = os.Remove(link)
+		if err := os.Symlink(target, link); err != nil {
+			return fmt.Errorf("link bin %s: %w", cmdName, err)
+		}
+	}
+
+	return nil
+}
+
+// runLifecycleScripts runs pkg's preinstall/install/postinstall hooks
+// (skipping any that aren't defined), with PATH extended to include the
+// project's node_modules/.bin and npm_package_* environment variables
+// set, mirroring npm's own lifecycle script environment.
+//
+// Running arbitrary scripts on install is a well-known supply-chain
+// hazard, so this is gated by allowed, which callers derive from the
+// global --ignore-scripts flag and the goose.toml [scripts] allow/deny
+// lists.
+func runLifecycleScripts(ctx context.Context, pkg PackageInfo, dir string, allowed bool) error {
+	if !allowed {
+		return nil
+	}
+
+	pj, err := readPackageJSON(dir)
+	if err != nil {
+		return err
+	}
+
+	binDir, err := filepath.Abs(filepath.Join("node_modules", ".bin"))
+	if err != nil {
+		return fmt.Errorf("resolve node_modules/.bin: %w", err)
+	}
+
+	for _, hook := range lifecycleHooks {
+		script := pj.Scripts[hook]
+		if script == "" {
+			continue
+		}
+
+		fmt.Printf("Running %s@%s %s: %s\n", pkg.Name, pkg.Version, hook, script)
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", script)
+		cmd.Dir = dir
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(os.Environ(),
+			"PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"),
+			"npm_package_name="+pkg.Name,
+			"npm_package_version="+pkg.Version,
+		)
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s script for %s@%s: %w", hook, pkg.Name, pkg.Version, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,270 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The store is laid out as:
+//
+//	<cache>/goose/cas/<sha256[:2]>/<sha256>        content-addressable blobs
+//	<cache>/goose/pkgs/<name>/<version>/...        hardlinks into the CAS
+//
+// Every installed version of every package shares blobs for identical
+// files (e.g. a LICENSE or a vendored copy of the same file across
+// versions), and two projects on disk never duplicate package contents,
+// except for packages with lifecycle scripts or bin entries, which install
+// copies into each project's virtual store (see install.go).
+const (
+	manifestFile = ".goose-manifest.json"
+)
+
+// packageManifest records, for a single pkgs/<name>/<version> tree, the
+// sha256 of every regular file it contains, keyed by its path relative to
+// the package root. `goose store prune` uses this to find blobs that are
+// still referenced before deleting anything from the CAS.
+type packageManifest struct {
+	Files map[string]string `json:"files"`
+}
+
+func storeRoot() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("get user cache dir: %w", err)
+	}
+	return filepath.Join(cacheDir, "goose"), nil
+}
+
+func casDir(store string) string {
+	return filepath.Join(store, "cas")
+}
+
+func pkgsDir(store string) string {
+	return filepath.Join(store, "pkgs")
+}
+
+func casPath(store, sum string) string {
+	return filepath.Join(casDir(store), sum[:2], sum)
+}
+
+func pkgSafeName(name string) string {
+	return strings.ReplaceAll(name, "/", "_")
+}
+
+func pkgVersionDir(store string, pkg PackageInfo) string {
+	return filepath.Join(pkgsDir(store), pkgSafeName(pkg.Name), pkg.Version)
+}
+
+// writeBlob streams r into the CAS, hashing as it goes, and returns the
+// resulting sha256 hex digest. It writes to a temp file first and renames
+// it into place so a concurrent reader of the same blob never observes a
+// partial write.
+func writeBlob(store string, r io.Reader) (sum string, err error) {
+	dir := casDir(store)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("mkdir cas: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "blob-*")
+	if err != nil {
+		return "", fmt.Errorf("create temp blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hasher), r); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("close temp blob: %w", err)
+	}
+
+	sum = hex.EncodeToString(hasher.Sum(nil))
+	dest := casPath(store, sum)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", fmt.Errorf("mkdir cas shard: %w", err)
+	}
+
+	if _, err := os.Stat(dest); err == nil {
+		return sum, nil // identical content already stored elsewhere
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return "", fmt.Errorf("rename blob into cas: %w", err)
+	}
+	return sum, nil
+}
+
+// linkBlob materializes a CAS blob at target via a hardlink, falling back
+// to a plain copy if the CAS and target don't share a filesystem.
+func linkBlob(blobPath, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("mkdir parent %s: %w", target, err)
+	}
+	_ = os.Remove(target)
+	if err := os.Link(blobPath, target); err != nil {
+		if copyErr := copyFile(blobPath, target); copyErr != nil {
+			return fmt.Errorf("hardlink failed (%v) and copy fallback failed: %w", err, copyErr)
+		}
+	}
+	return nil
+}
+
+// copyTree recursively copies src into dst, preserving directory structure,
+// file modes, and symlinks. Unlike linkBlob/os.Link, every file it produces
+// is an independent copy rather than a hardlink, so the destination can be
+// safely mutated (e.g. by a lifecycle script or linkBin's chmod) without
+// affecting src or anything else that shares src's inodes.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return fmt.Errorf("relativize %s: %w", path, err)
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("stat %s: %w", path, err)
+		}
+
+		switch {
+		case d.Type()&os.ModeSymlink != 0:
+			linkname, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("readlink %s: %w", path, err)
+			}
+			if err := os.Symlink(linkname, target); err != nil {
+				return fmt.Errorf("symlink %s -> %s: %w", target, linkname, err)
+			}
+		case d.IsDir():
+			if err := os.MkdirAll(target, info.Mode()); err != nil {
+				return fmt.Errorf("mkdir %s: %w", target, err)
+			}
+		default:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return fmt.Errorf("mkdir parent %s: %w", target, err)
+			}
+			if err := copyFile(path, target); err != nil {
+				return fmt.Errorf("copy %s -> %s: %w", path, target, err)
+			}
+			if err := os.Chmod(target, info.Mode()); err != nil {
+				return fmt.Errorf("chmod %s: %w", target, err)
+			}
+		}
+		return nil
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+func writeManifest(pkgDir string, files map[string]string) error {
+	data, err := json.MarshalIndent(packageManifest{Files: files}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(pkgDir, manifestFile), data, 0644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+func readManifest(pkgDir string) (packageManifest, error) {
+	data, err := os.ReadFile(filepath.Join(pkgDir, manifestFile))
+	if err != nil {
+		return packageManifest{}, err
+	}
+	var m packageManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return packageManifest{}, fmt.Errorf("parse manifest %s: %w", pkgDir, err)
+	}
+	return m, nil
+}
+
+// PruneStore removes every CAS blob that is no longer referenced by any
+// package manifest under pkgs/, and reports how many bytes were freed.
+func PruneStore() (freedBlobs int, freedBytes int64, err error) {
+	store, err := storeRoot()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	referenced := map[string]bool{}
+
+	err = filepath.WalkDir(pkgsDir(store), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || d.Name() != manifestFile {
+			return nil
+		}
+		m, err := readManifest(filepath.Dir(path))
+		if err != nil {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+		for _, sum := range m.Files {
+			referenced[sum] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("walk pkgs: %w", err)
+	}
+
+	err = filepath.WalkDir(casDir(store), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if referenced[d.Name()] {
+			return nil
+		}
+		info, statErr := d.Info()
+		if statErr == nil {
+			freedBytes += info.Size()
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("remove blob %s: %w", path, err)
+		}
+		freedBlobs++
+		return nil
+	})
+	if err != nil {
+		return freedBlobs, freedBytes, fmt.Errorf("walk cas: %w", err)
+	}
+
+	return freedBlobs, freedBytes, nil
+}